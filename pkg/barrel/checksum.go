@@ -0,0 +1,87 @@
+package barrel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ChecksumSlotSize is the fixed on-disk width of Header.Checksum. It is sized to the
+// largest digest barreldb supports (SHA-256) so the header layout never needs to change
+// again when a new ChecksumType is added; shorter digests just leave the tail zeroed.
+const ChecksumSlotSize = 32
+
+// ChecksumType identifies the hash algorithm a record's checksum was computed with.
+// It is persisted as a single byte in the header so every record is self-describing,
+// which lets a DB opened with one algorithm still read records written under another.
+type ChecksumType uint8
+
+const (
+	// ChecksumCRC32IEEE is the original barreldb checksum and remains the default. It is
+	// the only algorithm the legacy 20-byte header used, so UpgradeLegacyRecord tags every
+	// record it upgrades with it.
+	ChecksumCRC32IEEE ChecksumType = iota
+	// ChecksumCRC32C uses the Castagnoli polynomial, which modern CPUs compute with a
+	// dedicated SSE4.2 instruction and is noticeably cheaper than CRC32-IEEE in software.
+	ChecksumCRC32C
+	// ChecksumXXHash64 trades a larger digest for higher throughput than either CRC32 variant.
+	ChecksumXXHash64
+	// ChecksumSHA256 is the slowest option but gives cryptographic integrity guarantees.
+	ChecksumSHA256
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Hasher returns a new hash.Hash for the given ChecksumType. Record.isValidChecksum and
+// Record.setChecksum dispatch through this so the rest of the package never hardcodes a
+// specific algorithm. Unrecognized types fall back to CRC32-IEEE, matching the historical
+// on-disk default.
+func Hasher(t ChecksumType) hash.Hash {
+	switch t {
+	case ChecksumCRC32C:
+		return crc32.New(castagnoliTable)
+	case ChecksumXXHash64:
+		return xxhash.New()
+	case ChecksumSHA256:
+		return sha256.New()
+	default:
+		return crc32.NewIEEE()
+	}
+}
+
+// CompactChecksums reads FormatFixed records from r until EOF and writes each one back to
+// w, rewriting its checksum under newAlgo first if it was written with a different
+// ChecksumType. This is the migration mode compaction uses to copy a segment written
+// under an old algorithm into a new segment under newAlgo, rather than leaving
+// mixed-algorithm records scattered across the log indefinitely; records are not rewritten
+// in place, so r and w must be different streams.
+func CompactChecksums(w io.Writer, r io.Reader, newAlgo ChecksumType) error {
+	for {
+		rec, err := ReadRecord(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if rec.Header.Algo != newAlgo {
+			rec.setChecksum(newAlgo)
+		}
+
+		buf := &bytes.Buffer{}
+		if err := rec.Header.encode(buf); err != nil {
+			return err
+		}
+		buf.WriteString(rec.Key)
+		buf.Write(rec.Value)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package barrel
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompactChecksumsRewritesAlgo(t *testing.T) {
+	rec := &Record{Key: "k", Value: []byte("hello world")}
+	rec.setChecksum(ChecksumCRC32IEEE)
+	rec.Header.KeySize = uint32(len(rec.Key))
+	rec.Header.ValSize = uint32(len(rec.Value))
+
+	src := &bytes.Buffer{}
+	if err := rec.Header.encode(src); err != nil {
+		t.Fatalf("encode header: %v", err)
+	}
+	src.WriteString(rec.Key)
+	src.Write(rec.Value)
+
+	dst := &bytes.Buffer{}
+	if err := CompactChecksums(dst, src, ChecksumXXHash64); err != nil {
+		t.Fatalf("CompactChecksums: %v", err)
+	}
+
+	got, err := ReadRecord(dst)
+	if err != nil {
+		t.Fatalf("ReadRecord after compaction: %v", err)
+	}
+	if got.Header.Algo != ChecksumXXHash64 {
+		t.Fatalf("Algo = %v, want ChecksumXXHash64", got.Header.Algo)
+	}
+	if !got.isValidChecksum() {
+		t.Fatalf("compacted record has an invalid checksum under its new algo")
+	}
+}
@@ -0,0 +1,13 @@
+package barrel
+
+import "errors"
+
+var (
+	// ErrHeaderCorrupt is returned when a record's HeaderCRC does not match its decoded
+	// fields. KeySize and ValSize must not be trusted when this is returned: the record
+	// reader stops here rather than sizing an allocation off a potentially bogus length.
+	ErrHeaderCorrupt = errors.New("barrel: header checksum mismatch")
+	// ErrValueCorrupt is returned when a record's value fails the checksum recorded in an
+	// otherwise structurally valid header.
+	ErrValueCorrupt = errors.New("barrel: value checksum mismatch")
+)
@@ -0,0 +1,140 @@
+package barrel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// minSectorSize is the alignment granularity FramingAligned pads records to, matching the
+// physical sector size most storage assumes - the same constant etcd's WAL aligns its
+// frames to (minSectorSize = 512 there too).
+const minSectorSize = 512
+
+// frameTrailerSize is the fixed width of the trailer appended after a record's padding:
+// 4 bytes recordLen, 4 bytes padLen.
+const frameTrailerSize = 8
+
+// ErrFrameDesync is returned when a frame trailer doesn't match the record and padding
+// that were just read. At that point the stream position can no longer be trusted, and
+// the caller should resynchronize with ResyncAligned rather than retry from here.
+var ErrFrameDesync = errors.New("barrel: frame trailer desync")
+
+// WriteAlignedRecord writes rec using FramingAligned: the record's normal encoding,
+// zero padding, then an 8-byte trailer, such that the total bytes written is always a
+// multiple of minSectorSize. Because a torn write can now only ever land inside one
+// record's sector-aligned span, fsync-per-record becomes safe to reason about - a crash
+// mid-write corrupts exactly one record's worth of sectors, never bleeds into the next.
+// seq assigns rec.Header.SeqNum; it must be shared across every writer appending to the
+// same log so SeqNum stays monotonic log-wide.
+func WriteAlignedRecord(w io.Writer, rec *Record, seq *SeqNumCounter) error {
+	rec.Header.SeqNum = seq.Next()
+
+	buf := &bytes.Buffer{}
+	if err := rec.Header.encode(buf); err != nil {
+		return err
+	}
+	buf.WriteString(rec.Key)
+	buf.Write(rec.Value)
+
+	recordLen := buf.Len()
+	padLen := alignedPadLen(recordLen)
+	buf.Write(make([]byte, padLen))
+
+	var trailer [frameTrailerSize]byte
+	binary.LittleEndian.PutUint32(trailer[:4], uint32(recordLen))
+	binary.LittleEndian.PutUint32(trailer[4:], uint32(padLen))
+	buf.Write(trailer[:])
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// alignedPadLen returns the zero padding needed so that recordLen+frameTrailerSize rounds
+// up to a multiple of minSectorSize.
+func alignedPadLen(recordLen int) int {
+	rem := (recordLen + frameTrailerSize) % minSectorSize
+	if rem == 0 {
+		return 0
+	}
+	return minSectorSize - rem
+}
+
+// ReadAlignedRecord reads one FramingAligned record from r: the record itself, its zero
+// padding, and the trailer confirming both lengths. r must be an io.ReadSeeker: on any
+// failure, ReadAlignedRecord seeks r back to the offset the frame started at before
+// returning, so the stream position after an error is always exactly the failed frame's
+// start - never somewhere in the middle of it. ResyncAligned relies on that guarantee to
+// compute a correct skip distance. ReadAlignedRecord returns ErrFrameDesync if the
+// trailer doesn't match what was actually read, rather than the underlying decode error,
+// since a mismatched trailer means the record itself cannot be trusted even though it
+// parsed.
+func ReadAlignedRecord(r io.ReadSeeker) (*Record, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := readAlignedRecordBody(r, start)
+	if err != nil {
+		if _, serr := r.Seek(start, io.SeekStart); serr != nil {
+			return nil, serr
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+// readAlignedRecordBody does the actual decode work for ReadAlignedRecord, leaving the
+// rewind-on-failure to the caller so it has exactly one place to do it.
+func readAlignedRecordBody(r io.ReadSeeker, start int64) (*Record, error) {
+	rec, err := ReadRecord(r)
+	if err != nil {
+		return nil, err
+	}
+
+	afterRecord, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	recordLen := int(afterRecord - start)
+	padLen := alignedPadLen(recordLen)
+
+	pad := make([]byte, padLen)
+	if _, err := io.ReadFull(r, pad); err != nil {
+		return nil, err
+	}
+	for _, b := range pad {
+		if b != 0 {
+			return nil, ErrFrameDesync
+		}
+	}
+
+	trailer := make([]byte, frameTrailerSize)
+	if _, err := io.ReadFull(r, trailer); err != nil {
+		return nil, err
+	}
+	gotRecordLen := binary.LittleEndian.Uint32(trailer[:4])
+	gotPadLen := binary.LittleEndian.Uint32(trailer[4:])
+	if int(gotRecordLen) != recordLen || int(gotPadLen) != padLen {
+		return nil, ErrFrameDesync
+	}
+
+	return rec, nil
+}
+
+// ResyncAligned recovers from ErrFrameDesync (or any ReadAlignedRecord error) by seeking
+// forward exactly one sector from the current position. This is only correct because
+// ReadAlignedRecord always leaves r positioned at the start of the frame it failed to
+// decode - a sector-aligned offset by construction - so skipping one more sector from
+// there lands on the next candidate frame boundary. Skipping one sector from wherever
+// a read happened to stop, rather than from the last known frame boundary, would keep
+// landing on the same non-aligned residue forever; threading the boundary through via
+// ReadAlignedRecord's rewind contract is what makes repeated
+// ResyncAligned+ReadAlignedRecord calls converge on the next valid frame within a bounded
+// number of sectors.
+func ResyncAligned(r io.ReadSeeker) error {
+	_, err := r.Seek(minSectorSize, io.SeekCurrent)
+	return err
+}
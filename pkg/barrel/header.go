@@ -18,9 +18,34 @@ In a practical sense, this is also constrained by the memory of the underlying V
 where this program would run.
 
 Representation of the record stored on disk.
-------------------------------------------------------------------------------
-| crc(4) | time(4) | expiry (4) | key_size(4) | val_size(4) | key | val      |
-------------------------------------------------------------------------------
+------------------------------------------------------------------------------------------------------------------------
+| checksum(32) | time(4) | expiry (4) | key_size(4) | val_size(4) | seq_num(8) | algo(1) | rsvd(3) | header_crc(4) | key | val |
+------------------------------------------------------------------------------------------------------------------------
+
+SeqNum is a monotonically increasing number assigned across the whole log, not just one
+segment, so a reader recovering after a crash can resume from the highest SeqNum it saw
+rather than re-scanning every segment from the start.
+
+The checksum field is a fixed 32-byte slot sized to hold the largest supported
+digest (SHA-256). Algorithms with a shorter digest only use a prefix of it; the
+remaining bytes are zero-filled. Which prefix is meaningful is determined by
+the Algo field, so once a datafile is in this layout, it stays readable as new
+ChecksumTypes are introduced alongside existing ones.
+
+This layout is NOT compatible with the original 20-byte fixed header
+(crc(4)|time(4)|expiry(4)|key_size(4)|val_size(4)) that predates ChecksumType,
+SeqNum and HeaderCRC: the fields were redefined in place rather than appended,
+and there is no version marker a reader could use to tell the two layouts
+apart by content alone. ReadRecord/Header.decode only understand this layout.
+A datafile written before this series must be upgraded explicitly, record by
+record, with UpgradeLegacyRecord; it is never detected or read automatically.
+
+HeaderCRC trails the rest of the header and covers only those preceding header
+fields, not Key or Value. This lets a reader validate KeySize/ValSize before
+trusting them for anything: a torn or corrupt header is caught by HeaderCRC
+alone, before a single byte of key/value is read or allocated. The Checksum
+field above remains a separate, second check covering Value, verified only
+once the header itself is known-good.
 */
 type Record struct {
 	Header Header
@@ -30,21 +55,51 @@ type Record struct {
 
 // Header represents the fixed width fields present at the start of every record.
 type Header struct {
-	Checksum  uint32
+	Checksum  [ChecksumSlotSize]byte
 	Timestamp uint32
 	Expiry    uint32
 	KeySize   uint32
 	ValSize   uint32
+	SeqNum    uint64 // monotonically increasing across the whole log, not per-segment
+	Algo      ChecksumType
+	_         [3]byte // reserved for future use, keeps the header word-aligned
+	HeaderCRC uint32  // CRC32-IEEE over the preceding header fields only
+}
+
+// headerCRCFields returns the encoded bytes HeaderCRC is computed over: every header
+// field except HeaderCRC itself.
+func (h *Header) headerCRCFields() []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(h.Checksum[:])
+	binary.Write(buf, binary.LittleEndian, h.Timestamp)
+	binary.Write(buf, binary.LittleEndian, h.Expiry)
+	binary.Write(buf, binary.LittleEndian, h.KeySize)
+	binary.Write(buf, binary.LittleEndian, h.ValSize)
+	binary.Write(buf, binary.LittleEndian, h.SeqNum)
+	buf.WriteByte(byte(h.Algo))
+	buf.Write(make([]byte, 3))
+	return buf.Bytes()
 }
 
-// Encode takes a byte buffer, encodes the value of header and writes to the buffer.
+// Encode takes a byte buffer, encodes the value of header and writes to the buffer. It
+// computes HeaderCRC over the rest of the header before writing, so Decode can detect a
+// torn or corrupt header without having read KeySize/ValSize's worth of key/value yet.
 func (h *Header) encode(buf *bytes.Buffer) error {
+	h.HeaderCRC = crc32.ChecksumIEEE(h.headerCRCFields())
 	return binary.Write(buf, binary.LittleEndian, h)
 }
 
-// Decode takes a record object decodes the binary value the buffer.
+// Decode takes a record object decodes the binary value the buffer. It returns
+// ErrHeaderCorrupt if HeaderCRC does not match the decoded fields, before the caller has
+// any chance to read KeySize+ValSize bytes based on a potentially bogus length.
 func (h *Header) decode(record []byte) error {
-	return binary.Read(bytes.NewReader(record), binary.LittleEndian, h)
+	if err := binary.Read(bytes.NewReader(record), binary.LittleEndian, h); err != nil {
+		return err
+	}
+	if crc32.ChecksumIEEE(h.headerCRCFields()) != h.HeaderCRC {
+		return ErrHeaderCorrupt
+	}
+	return nil
 }
 
 // isExpired returns true if the key has already expired.
@@ -56,7 +111,25 @@ func (r *Record) isExpired() bool {
 	return time.Now().Unix() > int64(r.Header.Expiry)
 }
 
-// isValidChecksum returns true if the checksum of the value matches what is stored in the header.
+// isValidChecksum returns true if the checksum of the value matches what is stored in the
+// header, computed with the algorithm the record itself is tagged with. This only checks
+// Value; callers must verify the header itself first (Header.decode does this via
+// HeaderCRC) since KeySize/ValSize cannot be trusted otherwise.
 func (r *Record) isValidChecksum() bool {
-	return crc32.ChecksumIEEE(r.Value) == r.Header.Checksum
+	h := Hasher(r.Header.Algo)
+	h.Write(r.Value)
+	sum := h.Sum(nil)
+	return bytes.Equal(r.Header.Checksum[:len(sum)], sum)
+}
+
+// setChecksum computes the checksum of r.Value with algo and stores it (and the algo tag)
+// in the header, zero-filling the unused tail of the checksum slot.
+func (r *Record) setChecksum(algo ChecksumType) {
+	h := Hasher(algo)
+	h.Write(r.Value)
+	sum := h.Sum(nil)
+
+	r.Header.Algo = algo
+	r.Header.Checksum = [ChecksumSlotSize]byte{}
+	copy(r.Header.Checksum[:], sum)
 }
@@ -0,0 +1,68 @@
+package barrel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// LegacyHeaderSize is the fixed on-disk width of LegacyHeader.
+const LegacyHeaderSize = 20
+
+// LegacyHeader is the original fixed header format barreldb used before ChecksumType,
+// SeqNum and HeaderCRC existed: crc(4) | time(4) | expiry(4) | key_size(4) | val_size(4).
+// Checksum here is always a plain CRC32-IEEE over Value - the only algorithm that format
+// ever supported.
+type LegacyHeader struct {
+	Checksum  uint32
+	Timestamp uint32
+	Expiry    uint32
+	KeySize   uint32
+	ValSize   uint32
+}
+
+func (h *LegacyHeader) decode(record []byte) error {
+	return binary.Read(bytes.NewReader(record), binary.LittleEndian, h)
+}
+
+// UpgradeLegacyRecord reads one record encoded with LegacyHeader from r, verifies its
+// CRC32-IEEE checksum, and returns it as a current Record: Algo is set to
+// ChecksumCRC32IEEE (the only algorithm the legacy format ever used), SeqNum is left at 0
+// since the legacy format had no notion of one, and Checksum is recomputed. HeaderCRC is
+// left for Header.encode to compute when the record is written, so the result round-trips
+// through WriteRecordFormat/ReadRecord like any record written by this series. This is the only supported path from a pre-series datafile to the current
+// layout - nothing in the package detects or reads the legacy layout automatically.
+func UpgradeLegacyRecord(r io.Reader) (*Record, error) {
+	raw := make([]byte, LegacyHeaderSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+
+	var lh LegacyHeader
+	if err := lh.decode(raw); err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, int(lh.KeySize)+int(lh.ValSize))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	value := body[lh.KeySize:]
+	if crc32.ChecksumIEEE(value) != lh.Checksum {
+		return nil, ErrValueCorrupt
+	}
+
+	rec := &Record{
+		Key:   string(body[:lh.KeySize]),
+		Value: value,
+	}
+	rec.Header.Timestamp = lh.Timestamp
+	rec.Header.Expiry = lh.Expiry
+	rec.Header.KeySize = lh.KeySize
+	rec.Header.ValSize = lh.ValSize
+	rec.setChecksum(ChecksumCRC32IEEE)
+
+	return rec, nil
+}
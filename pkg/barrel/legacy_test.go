@@ -0,0 +1,65 @@
+package barrel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func encodeLegacyRecord(t *testing.T, key string, value []byte) []byte {
+	t.Helper()
+	lh := LegacyHeader{
+		Checksum: crc32.ChecksumIEEE(value),
+		KeySize:  uint32(len(key)),
+		ValSize:  uint32(len(value)),
+	}
+
+	buf := &bytes.Buffer{}
+	if err := binary.Write(buf, binary.LittleEndian, lh); err != nil {
+		t.Fatalf("encode legacy header: %v", err)
+	}
+	buf.WriteString(key)
+	buf.Write(value)
+	return buf.Bytes()
+}
+
+func TestUpgradeLegacyRecord(t *testing.T) {
+	raw := encodeLegacyRecord(t, "k", []byte("hello"))
+
+	rec, err := UpgradeLegacyRecord(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("UpgradeLegacyRecord: %v", err)
+	}
+	if rec.Key != "k" || !bytes.Equal(rec.Value, []byte("hello")) {
+		t.Fatalf("got key=%q value=%q, want key=%q value=%q", rec.Key, rec.Value, "k", "hello")
+	}
+	if rec.Header.Algo != ChecksumCRC32IEEE {
+		t.Fatalf("Algo = %v, want ChecksumCRC32IEEE", rec.Header.Algo)
+	}
+
+	// The upgraded record must round-trip through the current format.
+	upgraded := &bytes.Buffer{}
+	if err := rec.Header.encode(upgraded); err != nil {
+		t.Fatalf("encode upgraded header: %v", err)
+	}
+	upgraded.WriteString(rec.Key)
+	upgraded.Write(rec.Value)
+
+	got, err := ReadRecord(upgraded)
+	if err != nil {
+		t.Fatalf("ReadRecord after upgrade: %v", err)
+	}
+	if got.Key != rec.Key || !bytes.Equal(got.Value, rec.Value) {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, rec)
+	}
+}
+
+func TestUpgradeLegacyRecordDetectsCorruption(t *testing.T) {
+	raw := encodeLegacyRecord(t, "k", []byte("hello"))
+	raw[0] ^= 0xff // corrupt the legacy CRC
+
+	if _, err := UpgradeLegacyRecord(bytes.NewReader(raw)); err != ErrValueCorrupt {
+		t.Fatalf("err = %v, want ErrValueCorrupt", err)
+	}
+}
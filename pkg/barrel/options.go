@@ -0,0 +1,58 @@
+package barrel
+
+// HeaderFormat selects the on-disk encoding used for Header. It is chosen once at DB
+// open time. It is not stored in the datafile or in the header itself: a reader must be
+// passed the HeaderFormat the file was written with (see ReadRecordFormat).
+type HeaderFormat uint8
+
+const (
+	// FormatFixed is the 64-byte fixed-width header (Checksum slot, four uint32 fields,
+	// SeqNum, Algo, reserved bytes and HeaderCRC). It is simplest to reason about and is
+	// the default. It is not the legacy 20-byte header; see UpgradeLegacyRecord.
+	FormatFixed HeaderFormat = iota
+	// FormatVarint packs Timestamp, Expiry, KeySize, ValSize and SeqNum as varints instead
+	// of fixed-width uint32/uint64 fields, shrinking the header relative to FormatFixed's
+	// 64 bytes for workloads dominated by small records - though the fixed 32-byte
+	// checksum slot means it never gets close to the original pre-ChecksumType 20-byte
+	// header; see Header.encodeVarint for the real numbers.
+	FormatVarint
+)
+
+// Framing selects whether records are packed back-to-back or padded to sector boundaries.
+type Framing uint8
+
+const (
+	// FramingNone packs records back-to-back with no padding, as barreldb always has.
+	FramingNone Framing = iota
+	// FramingAligned pads each record with zeros and a trailing frame so its total
+	// on-disk footprint is a multiple of minSectorSize. See WriteAlignedRecord.
+	FramingAligned
+)
+
+// Options configures a Barrel DB at open time.
+type Options struct {
+	// ChecksumType selects the hash algorithm used for new records. Existing records keep
+	// whatever ChecksumType they were written with; Header.Algo is always authoritative.
+	ChecksumType ChecksumType
+	// HeaderFormat selects the header encoding used for records written by this DB instance.
+	HeaderFormat HeaderFormat
+	// SegmentSize is the maximum size in bytes of a single datafile segment before it is
+	// closed and a new active segment is rolled. Smaller segments roll (and so become
+	// tailable/backup-able) more often, at the cost of more open file descriptors over the
+	// life of the DB; larger segments do the opposite. Zero means DefaultSegmentSize.
+	SegmentSize int64
+	// Framing selects whether records are sector-aligned (FramingAligned) or packed
+	// back-to-back (FramingNone, the default). Existing files written as FramingNone keep
+	// decoding correctly since the two framings are never mixed within one datafile.
+	Framing Framing
+}
+
+// DefaultOptions returns the Options a Barrel DB uses when none are supplied.
+func DefaultOptions() Options {
+	return Options{
+		ChecksumType: ChecksumCRC32IEEE,
+		HeaderFormat: FormatFixed,
+		SegmentSize:  DefaultSegmentSize,
+		Framing:      FramingNone,
+	}
+}
@@ -0,0 +1,41 @@
+package barrel
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// HeaderSize is the fixed on-disk width of a FormatFixed header, including HeaderCRC.
+var HeaderSize = binary.Size(Header{})
+
+// ReadRecord reads one FormatFixed-encoded record from r. It reads and validates the
+// header before reading a single byte of key/value: a corrupted KeySize or ValSize is
+// caught by ErrHeaderCorrupt instead of being trusted to size the next read, which is
+// what let a torn header allocate an unbounded buffer before this existed. Only once the
+// header passes its own check is the value read and checked against Header.Checksum.
+func ReadRecord(r io.Reader) (*Record, error) {
+	raw := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+
+	var h Header
+	if err := h.decode(raw); err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, int(h.KeySize)+int(h.ValSize))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	rec := &Record{
+		Header: h,
+		Key:    string(body[:h.KeySize]),
+		Value:  body[h.KeySize:],
+	}
+	if !rec.isValidChecksum() {
+		return nil, ErrValueCorrupt
+	}
+	return rec, nil
+}
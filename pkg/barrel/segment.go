@@ -0,0 +1,150 @@
+package barrel
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSegmentSize is used when Options.SegmentSize is zero. Bitcask-style stores roll
+// the active datafile once it crosses a threshold like this rather than growing one file
+// forever; Prometheus's WALSegmentSize exists for the same reason - it bounds how much a
+// single torn write, or a single lost segment, can cost.
+const DefaultSegmentSize int64 = 64 * 1024 * 1024
+
+// Segment identifies one rolled datafile in a segmented, append-only log.
+type Segment struct {
+	SeqNum int64
+	Path   string
+}
+
+// SegmentWriter wraps the active segment's underlying writer and tracks how many bytes
+// have been appended to it, so a caller writing records one at a time can tell when this
+// segment has crossed its size threshold and should be closed in favor of a newly rolled
+// one, rather than growing a single datafile forever.
+type SegmentWriter struct {
+	Segment Segment
+
+	w       io.Writer
+	written int64
+	maxSize int64
+}
+
+// NewSegmentWriter returns a SegmentWriter appending to w, the already-open underlying
+// file for segment. maxSize is the threshold ShouldRoll checks written bytes against; zero
+// means DefaultSegmentSize, matching how Options.SegmentSize treats zero.
+func NewSegmentWriter(segment Segment, w io.Writer, maxSize int64) *SegmentWriter {
+	if maxSize == 0 {
+		maxSize = DefaultSegmentSize
+	}
+	return &SegmentWriter{Segment: segment, w: w, maxSize: maxSize}
+}
+
+// Write appends p to the segment, tracking bytes written so ShouldRoll can later report
+// whether maxSize has been crossed. sw can be passed directly as the io.Writer argument to
+// WriteRecordFormat or WriteAlignedRecord.
+func (sw *SegmentWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	sw.written += int64(n)
+	return n, err
+}
+
+// Written returns the number of bytes appended to the segment so far.
+func (sw *SegmentWriter) Written() int64 {
+	return sw.written
+}
+
+// ShouldRoll reports whether the segment has crossed its size threshold and should be
+// closed in favor of a newly created one. It is meant to be checked after each record is
+// fully written, never mid-record: a segment only ever rolls between records, the same way
+// Prometheus's WAL only rolls a segment between full writes.
+func (sw *SegmentWriter) ShouldRoll() bool {
+	return sw.written >= sw.maxSize
+}
+
+// SeqNumCounter issues the monotonically increasing values stored in Header.SeqNum across
+// an entire log, not just one segment, so a Tailer or crash recovery can resume from the
+// highest SeqNum actually observed. The zero value starts issuing from 1. Safe for
+// concurrent use.
+type SeqNumCounter struct {
+	n uint64
+}
+
+// Next returns the next sequence number.
+func (c *SeqNumCounter) Next() uint64 {
+	return atomic.AddUint64(&c.n, 1)
+}
+
+// SetMax advances the counter to at least n, without going backwards. Recovery uses this
+// to resume issuing SeqNums after the highest one found in an existing log, rather than
+// restarting at 1 and colliding with values already on disk.
+func (c *SeqNumCounter) SetMax(n uint64) {
+	for {
+		cur := atomic.LoadUint64(&c.n)
+		if n <= cur {
+			return
+		}
+		if atomic.CompareAndSwapUint64(&c.n, cur, n) {
+			return
+		}
+	}
+}
+
+const tailerRetryInterval = 50 * time.Millisecond
+
+// Tailer reads Records from a segment as they are appended, in the spirit of etcd/wal's
+// LiveReader. Unlike a plain file reader, Next does not treat running out of bytes as
+// io.EOF when the segment it is reading is still the active one being written: more
+// records may still land. It only returns io.EOF once closed reports the segment has been
+// rolled and fully drained, which is what lets a Tailer back a replication/CDC follower or
+// an external backup tool without racing the writer.
+//
+// r must be an io.ReadSeeker: a partial record (the writer appended only some of a
+// record's bytes before Next caught up to it) leaves the underlying reader positioned
+// mid-record, and io.ReadFull never un-reads those bytes on its own. Next rewinds to the
+// start of the attempt before retrying, so a retry always re-parses the record from its
+// header rather than from wherever the previous attempt happened to stop.
+type Tailer struct {
+	r      io.ReadSeeker
+	closed func() bool
+
+	// retryInterval is how long Next sleeps between retries after hitting EOF on a still-
+	// open segment. Defaults to tailerRetryInterval; overridable for tests.
+	retryInterval time.Duration
+}
+
+// NewTailer returns a Tailer reading records from r. closed reports whether the segment r
+// reads from has been closed and rolled, letting Next distinguish "nothing appended yet"
+// from "this segment is done".
+func NewTailer(r io.ReadSeeker, closed func() bool) *Tailer {
+	return &Tailer{r: r, closed: closed, retryInterval: tailerRetryInterval}
+}
+
+// Next returns the next Record in the segment. If the segment is still active, Next
+// sleeps and retries instead of returning io.EOF, since a writer may still append more
+// records. It returns io.EOF only once closed reports the segment has been rolled and no
+// record remains to be read.
+func (t *Tailer) Next() (*Record, error) {
+	for {
+		offset, err := t.r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+
+		rec, err := ReadRecord(t.r)
+		if err == nil {
+			return rec, nil
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		if t.closed() {
+			return nil, io.EOF
+		}
+		if _, serr := t.r.Seek(offset, io.SeekStart); serr != nil {
+			return nil, serr
+		}
+		time.Sleep(t.retryInterval)
+	}
+}
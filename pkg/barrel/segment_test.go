@@ -0,0 +1,125 @@
+package barrel
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTailerSurvivesPartialWrite(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "segment")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	rec := &Record{Key: "k", Value: []byte("hello")}
+	rec.setChecksum(ChecksumCRC32IEEE)
+	rec.Header.KeySize = uint32(len(rec.Key))
+	rec.Header.ValSize = uint32(len(rec.Value))
+
+	encoded := &bytes.Buffer{}
+	if err := rec.Header.encode(encoded); err != nil {
+		t.Fatalf("encode header: %v", err)
+	}
+	encoded.WriteString(rec.Key)
+	encoded.Write(rec.Value)
+	full := encoded.Bytes()
+
+	// Write only part of the header, simulating a writer caught mid-append.
+	split := len(full) / 2
+	if _, err := f.Write(full[:split]); err != nil {
+		t.Fatalf("write partial: %v", err)
+	}
+
+	reader, err := os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer reader.Close()
+
+	var closed bool
+	tailer := NewTailer(reader, func() bool { return closed })
+	tailer.retryInterval = time.Millisecond
+
+	resultCh := make(chan *Record, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		got, err := tailer.Next()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		resultCh <- got
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := f.Write(full[split:]); err != nil {
+		t.Fatalf("write remainder: %v", err)
+	}
+
+	select {
+	case got := <-resultCh:
+		if got.Key != rec.Key || !bytes.Equal(got.Value, rec.Value) {
+			t.Fatalf("got %+v, want key=%q value=%q", got, rec.Key, rec.Value)
+		}
+	case err := <-errCh:
+		t.Fatalf("Next() returned error: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Next() did not return after the rest of the record was appended")
+	}
+}
+
+func TestSegmentWriterShouldRoll(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sw := NewSegmentWriter(Segment{SeqNum: 1, Path: "seg-1"}, buf, 10)
+
+	if sw.ShouldRoll() {
+		t.Fatal("ShouldRoll() = true before any bytes were written")
+	}
+
+	if _, err := sw.Write(make([]byte, 9)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if sw.Written() != 9 {
+		t.Fatalf("Written() = %d, want 9", sw.Written())
+	}
+	if sw.ShouldRoll() {
+		t.Fatal("ShouldRoll() = true before crossing maxSize")
+	}
+
+	if _, err := sw.Write(make([]byte, 1)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !sw.ShouldRoll() {
+		t.Fatal("ShouldRoll() = false after reaching maxSize")
+	}
+}
+
+func TestNewSegmentWriterDefaultsMaxSize(t *testing.T) {
+	sw := NewSegmentWriter(Segment{}, &bytes.Buffer{}, 0)
+	if sw.maxSize != DefaultSegmentSize {
+		t.Fatalf("maxSize = %d, want DefaultSegmentSize (%d)", sw.maxSize, DefaultSegmentSize)
+	}
+}
+
+func TestSeqNumCounterMonotonic(t *testing.T) {
+	var c SeqNumCounter
+	if got := c.Next(); got != 1 {
+		t.Fatalf("first Next() = %d, want 1", got)
+	}
+	if got := c.Next(); got != 2 {
+		t.Fatalf("second Next() = %d, want 2", got)
+	}
+
+	c.SetMax(10)
+	if got := c.Next(); got != 11 {
+		t.Fatalf("Next() after SetMax(10) = %d, want 11", got)
+	}
+
+	c.SetMax(5) // must not move the counter backwards
+	if got := c.Next(); got != 12 {
+		t.Fatalf("Next() after SetMax(5) = %d, want 12", got)
+	}
+}
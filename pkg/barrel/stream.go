@@ -0,0 +1,154 @@
+package barrel
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// StreamRecord mirrors Record for values too large to hold in memory at once: instead of
+// a []byte Value it carries an io.Reader of known Size. WriteStream and ReadStream neither
+// take nor return a StreamRecord: WriteStream takes the key, size and value reader as
+// separate arguments, and ReadStream returns the value as an io.ReadCloser. A streamed
+// record is indistinguishable on disk from one written via WriteRecordFormat with
+// FormatFixed.
+type StreamRecord struct {
+	Header Header
+	Key    string
+	Size   int64
+	Reader io.Reader
+}
+
+// WriteStream writes a streamed record to w: the header, then the key, then size bytes
+// copied from r, hashing as they pass through rather than requiring the value in memory
+// up front. Because the value checksum can only be known after the value has streamed
+// through, but the header must precede the value on disk, WriteStream writes a
+// provisional header first and seeks back to patch in the real checksum once r is
+// exhausted - so w must be an io.WriteSeeker, such as an *os.File positioned at the
+// record's offset. seq assigns the record's Header.SeqNum; it must be shared across every
+// writer appending to the same log so SeqNum stays monotonic log-wide.
+func WriteStream(w io.WriteSeeker, key string, size int64, r io.Reader, algo ChecksumType, expiry uint32, seq *SeqNumCounter) error {
+	headerOffset, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	h := Header{
+		Timestamp: uint32(time.Now().Unix()),
+		Expiry:    expiry,
+		KeySize:   uint32(len(key)),
+		ValSize:   uint32(size),
+		SeqNum:    seq.Next(),
+		Algo:      algo,
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.encode(buf); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, key); err != nil {
+		return err
+	}
+
+	hasher := Hasher(algo)
+	written, err := io.Copy(w, io.TeeReader(io.LimitReader(r, size), hasher))
+	if err != nil {
+		abortStream(w, headerOffset)
+		return err
+	}
+	if written != size {
+		abortStream(w, headerOffset)
+		return fmt.Errorf("barrel: stream ended after %d of %d declared bytes: %w", written, size, io.ErrUnexpectedEOF)
+	}
+	copy(h.Checksum[:], hasher.Sum(nil))
+
+	valueEnd, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := h.encode(buf); err != nil {
+		return err
+	}
+	if _, err := w.Seek(headerOffset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Seek(valueEnd, io.SeekStart)
+	return err
+}
+
+// abortStream best-effort truncates w back to headerOffset after a failed WriteStream, so
+// a torn value left by a short read or copy error doesn't sit on disk as unexplained
+// trailing garbage for the next reader to trip over, and seeks w back to headerOffset so a
+// caller that reuses w afterward (e.g. retrying the write) starts from a clean offset
+// rather than wherever the failed copy happened to leave the cursor. w is truncated rather
+// than left as is because the provisional header written at headerOffset already declares
+// a ValSize that the torn bytes no longer satisfy. Truncation is best-effort: w may not
+// implement Truncate (only *os.File among io.WriteSeekers typically does), in which case
+// abortStream seeks back but otherwise leaves the torn bytes in place; the caller's own
+// error is what gets returned either way.
+func abortStream(w io.WriteSeeker, headerOffset int64) {
+	if t, ok := w.(interface{ Truncate(int64) error }); ok {
+		t.Truncate(headerOffset)
+	}
+	w.Seek(headerOffset, io.SeekStart)
+}
+
+// streamValueReader is the io.ReadCloser ReadStream returns. Reads are served from a
+// bounded *io.SectionReader so the value is never read into memory as a whole; bytes are
+// hashed as they pass through Read and checked against the header's checksum on Close.
+type streamValueReader struct {
+	section *io.SectionReader
+	hasher  hash.Hash
+	header  Header
+}
+
+func (s *streamValueReader) Read(p []byte) (int, error) {
+	n, err := s.section.Read(p)
+	if n > 0 {
+		s.hasher.Write(p[:n])
+	}
+	return n, err
+}
+
+// Close verifies the bytes read through Read match the checksum recorded in the header.
+// It returns ErrValueCorrupt if the caller read the value to completion but it didn't
+// match; a caller that closes early without reading the full value gets no guarantee.
+func (s *streamValueReader) Close() error {
+	sum := s.hasher.Sum(nil)
+	if !bytes.Equal(s.header.Checksum[:len(sum)], sum) {
+		return ErrValueCorrupt
+	}
+	return nil
+}
+
+// ReadStream reads a streamed record's header at offset in ra and returns an
+// io.ReadCloser over just its value, bounded to exactly ValSize bytes so a caller can
+// stream arbitrarily large values - media chunks, backups - without the whole value ever
+// being resident in memory.
+func ReadStream(ra io.ReaderAt, offset int64) (io.ReadCloser, error) {
+	raw := make([]byte, HeaderSize)
+	if _, err := ra.ReadAt(raw, offset); err != nil {
+		return nil, err
+	}
+
+	var h Header
+	if err := h.decode(raw); err != nil {
+		return nil, err
+	}
+
+	valueOffset := offset + int64(HeaderSize) + int64(h.KeySize)
+	return &streamValueReader{
+		section: io.NewSectionReader(ra, valueOffset, int64(h.ValSize)),
+		hasher:  Hasher(h.Algo),
+		header:  h,
+	}, nil
+}
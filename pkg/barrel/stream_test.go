@@ -0,0 +1,82 @@
+package barrel
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteStreamRoundTrip(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stream")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	value := "hello streaming world"
+	seq := &SeqNumCounter{}
+	if err := WriteStream(f, "k", int64(len(value)), strings.NewReader(value), ChecksumCRC32IEEE, 0, seq); err != nil {
+		t.Fatalf("WriteStream: %v", err)
+	}
+
+	rc, err := ReadStream(f, 0)
+	if err != nil {
+		t.Fatalf("ReadStream: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(got) != value {
+		t.Fatalf("value = %q, want %q", got, value)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestWriteStreamShortReaderErrors(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stream")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	err = WriteStream(f, "k", 100, strings.NewReader("short"), ChecksumCRC32IEEE, 0, &SeqNumCounter{})
+	if err == nil {
+		t.Fatal("WriteStream with a short reader returned nil error, want ErrUnexpectedEOF")
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want wrapped io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestWriteStreamShortReaderTruncatesTornRecord(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stream")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	if err := WriteStream(f, "k", 100, strings.NewReader("short"), ChecksumCRC32IEEE, 0, &SeqNumCounter{}); err == nil {
+		t.Fatal("WriteStream with a short reader returned nil error")
+	}
+
+	got, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	if got != 0 {
+		t.Fatalf("file position after failed WriteStream = %d, want 0 (truncated back to headerOffset)", got)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("file size after failed WriteStream = %d, want 0 (torn record truncated away)", info.Size())
+	}
+}
@@ -0,0 +1,206 @@
+package barrel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// encodeVarint writes h to buf using FormatVarint: the checksum slot and algo tag stay
+// fixed width (a corrupted varint there would be unrecoverable), followed by Timestamp,
+// Expiry, KeySize, ValSize and SeqNum each packed with binary.PutUvarint, and a trailing
+// fixed 4-byte HeaderCRC covering everything before it - the same protection FormatFixed
+// gets from its own HeaderCRC.
+//
+// Because Header.Checksum is a 32-byte slot (sized for SHA-256, see ChecksumSlotSize),
+// the real minimum width here is 32 (checksum) + 1 (algo) + 5 (five single-byte varints)
+// + 4 (HeaderCRC) = 42 bytes, growing from there as Timestamp/Expiry/KeySize/ValSize/
+// SeqNum need more varint bytes. That is smaller than FormatFixed's 64-byte width, but it
+// does not shrink below the original pre-ChecksumType 20-byte header the way a varint
+// header over a 4-byte checksum would: the 32-byte slot dominates the total either way.
+// A caller must track the header's width via the length returned by decodeVarint (or
+// consumed internally by ReadRecordVarint), since it is no longer fixed.
+func (h *Header) encodeVarint(buf *bytes.Buffer) error {
+	body := &bytes.Buffer{}
+	body.Write(h.Checksum[:])
+	body.WriteByte(byte(h.Algo))
+
+	var tmp [binary.MaxVarintLen64]byte
+	for _, v := range []uint64{
+		uint64(h.Timestamp),
+		uint64(h.Expiry),
+		uint64(h.KeySize),
+		uint64(h.ValSize),
+		h.SeqNum,
+	} {
+		n := binary.PutUvarint(tmp[:], v)
+		body.Write(tmp[:n])
+	}
+
+	h.HeaderCRC = crc32.ChecksumIEEE(body.Bytes())
+	buf.Write(body.Bytes())
+	return binary.Write(buf, binary.LittleEndian, h.HeaderCRC)
+}
+
+// decodeVarint reads a FormatVarint-encoded header from the start of record and returns
+// the number of bytes consumed, including the trailing HeaderCRC. It returns
+// ErrHeaderCorrupt if HeaderCRC doesn't match, the same as Header.decode does for
+// FormatFixed.
+func (h *Header) decodeVarint(record []byte) (n int, err error) {
+	if len(record) < ChecksumSlotSize+1 {
+		return 0, fmt.Errorf("barrel: short header: need at least %d bytes, got %d", ChecksumSlotSize+1, len(record))
+	}
+
+	copy(h.Checksum[:], record[:ChecksumSlotSize])
+	h.Algo = ChecksumType(record[ChecksumSlotSize])
+	n = ChecksumSlotSize + 1
+
+	fields := []*uint32{&h.Timestamp, &h.Expiry, &h.KeySize, &h.ValSize}
+	for _, field := range fields {
+		v, width := binary.Uvarint(record[n:])
+		if width <= 0 {
+			return 0, fmt.Errorf("barrel: invalid varint header at offset %d", n)
+		}
+		*field = uint32(v)
+		n += width
+	}
+
+	seqNum, width := binary.Uvarint(record[n:])
+	if width <= 0 {
+		return 0, fmt.Errorf("barrel: invalid varint header at offset %d", n)
+	}
+	h.SeqNum = seqNum
+	n += width
+
+	if len(record) < n+4 {
+		return 0, fmt.Errorf("barrel: short varint header: missing trailing header CRC")
+	}
+	h.HeaderCRC = binary.LittleEndian.Uint32(record[n : n+4])
+	if crc32.ChecksumIEEE(record[:n]) != h.HeaderCRC {
+		return 0, ErrHeaderCorrupt
+	}
+	n += 4
+
+	return n, nil
+}
+
+// readUvarintTracked reads one uvarint from r one byte at a time (mirroring the algorithm
+// binary.Uvarint uses over a byte slice), appending every byte it consumes to crcBuf so
+// the caller can compute HeaderCRC over exactly the bytes that made up the header.
+func readUvarintTracked(r io.Reader, crcBuf *bytes.Buffer) (uint64, error) {
+	var x uint64
+	var s uint
+	var b [1]byte
+	for i := 0; ; i++ {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		crcBuf.WriteByte(b[0])
+		if b[0] < 0x80 {
+			if i > 9 || (i == 9 && b[0] > 1) {
+				return 0, fmt.Errorf("barrel: varint header field overflows uint64")
+			}
+			return x | uint64(b[0])<<s, nil
+		}
+		x |= uint64(b[0]&0x7f) << s
+		s += 7
+	}
+}
+
+// ReadRecordVarint reads one FormatVarint-encoded record from r. Unlike ReadRecord, it
+// cannot size a single fixed-width buffer up front since a varint header's width isn't
+// known until it has been parsed, so it reads the header one field at a time and advances
+// r by exactly as many bytes as decodeVarint would report consumed - the "iteration using
+// the returned consumed-length" FormatVarint exists to support. It returns
+// ErrHeaderCorrupt before reading any key/value bytes if the header's own CRC doesn't
+// match, the same guarantee ReadRecord gives for FormatFixed.
+func ReadRecordVarint(r io.Reader) (*Record, error) {
+	var h Header
+	crcBuf := &bytes.Buffer{}
+
+	head := make([]byte, ChecksumSlotSize+1)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	crcBuf.Write(head)
+	copy(h.Checksum[:], head[:ChecksumSlotSize])
+	h.Algo = ChecksumType(head[ChecksumSlotSize])
+
+	fields := []*uint32{&h.Timestamp, &h.Expiry, &h.KeySize, &h.ValSize}
+	for _, field := range fields {
+		v, err := readUvarintTracked(r, crcBuf)
+		if err != nil {
+			return nil, err
+		}
+		*field = uint32(v)
+	}
+
+	seqNum, err := readUvarintTracked(r, crcBuf)
+	if err != nil {
+		return nil, err
+	}
+	h.SeqNum = seqNum
+
+	var crcBytes [4]byte
+	if _, err := io.ReadFull(r, crcBytes[:]); err != nil {
+		return nil, err
+	}
+	h.HeaderCRC = binary.LittleEndian.Uint32(crcBytes[:])
+	if crc32.ChecksumIEEE(crcBuf.Bytes()) != h.HeaderCRC {
+		return nil, ErrHeaderCorrupt
+	}
+
+	body := make([]byte, int(h.KeySize)+int(h.ValSize))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	rec := &Record{
+		Header: h,
+		Key:    string(body[:h.KeySize]),
+		Value:  body[h.KeySize:],
+	}
+	if !rec.isValidChecksum() {
+		return nil, ErrValueCorrupt
+	}
+	return rec, nil
+}
+
+// WriteRecordVarint writes rec using FormatVarint.
+func WriteRecordVarint(w io.Writer, rec *Record) error {
+	buf := &bytes.Buffer{}
+	if err := rec.Header.encodeVarint(buf); err != nil {
+		return err
+	}
+	buf.WriteString(rec.Key)
+	buf.Write(rec.Value)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadRecordFormat reads one record from r using the given HeaderFormat, dispatching to
+// ReadRecordVarint or ReadRecord - this is the switch Options.HeaderFormat exists to drive
+// when a datafile is iterated.
+func ReadRecordFormat(r io.Reader, format HeaderFormat) (*Record, error) {
+	if format == FormatVarint {
+		return ReadRecordVarint(r)
+	}
+	return ReadRecord(r)
+}
+
+// WriteRecordFormat writes rec using the given HeaderFormat.
+func WriteRecordFormat(w io.Writer, rec *Record, format HeaderFormat) error {
+	if format == FormatVarint {
+		return WriteRecordVarint(w, rec)
+	}
+	buf := &bytes.Buffer{}
+	if err := rec.Header.encode(buf); err != nil {
+		return err
+	}
+	buf.WriteString(rec.Key)
+	buf.Write(rec.Value)
+	_, err := w.Write(buf.Bytes())
+	return err
+}